@@ -0,0 +1,99 @@
+package nameref
+
+import (
+	"fmt"
+	"sync"
+
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/api/resid"
+	"sigs.k8s.io/kustomize/api/resource"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+// KustomizationResolver is a ReferralResolver backed by one or more
+// sibling kustomization directories, searched in order. Each
+// directory is built at most once, the first time a lookup actually
+// reaches it, and the search stops at the first directory with a
+// match — a directory later in the list is never built if an earlier
+// one already resolved the reference.
+//
+// A caller wires this, and ManifestResolver, into Filter.ExternalResolvers
+// from an `externalReferrals:` block on the Kustomization; that block,
+// and the builtins.NameReferenceTransformer plumbing for it, don't
+// exist in this checkout (the ReferralResolution doc comment in
+// nameref.go says the same of its own config block).
+type KustomizationResolver struct {
+	fSys  filesys.FileSystem
+	paths []string
+
+	mu     sync.Mutex
+	built  map[string]map[string][]*resource.Resource
+	failed map[string]error
+}
+
+// NewKustomizationResolver returns a resolver that searches the
+// builds of the given sibling kustomization directories, in order.
+func NewKustomizationResolver(
+	fSys filesys.FileSystem, paths ...string) *KustomizationResolver {
+	return &KustomizationResolver{
+		fSys:   fSys,
+		paths:  paths,
+		built:  make(map[string]map[string][]*resource.Resource),
+		failed: make(map[string]error),
+	}
+}
+
+// gvkNameKey indexes and looks up targetsFor's map, ignoring Version:
+// a built ConfigMap's concrete Gvk carries "v1", but f.ReferralTarget
+// (what Resolve is asked to match) is usually version-less for
+// builtin kinds, so keying on the full Gvk.String() would never match.
+func gvkNameKey(gvk resid.Gvk, name string) string {
+	return gvk.Group + "/" + gvk.Kind + "|" + name
+}
+
+// targetsFor builds, and caches, the index of path's build output,
+// keyed by gvkNameKey.
+func (k *KustomizationResolver) targetsFor(path string) (map[string][]*resource.Resource, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if err, ok := k.failed[path]; ok {
+		return nil, err
+	}
+	if targets, ok := k.built[path]; ok {
+		return targets, nil
+	}
+	kustomizer := krusty.MakeKustomizer(krusty.MakeDefaultOptions())
+	m, err := kustomizer.Run(k.fSys, path)
+	if err != nil {
+		err = fmt.Errorf("unable to build sibling kustomization %s: %w", path, err)
+		k.failed[path] = err
+		return nil, err
+	}
+	targets := make(map[string][]*resource.Resource)
+	for _, res := range m.Resources() {
+		id := res.OrgId()
+		key := gvkNameKey(id.Gvk, res.GetOriginalName())
+		targets[key] = append(targets[key], res)
+	}
+	k.built[path] = targets
+	return targets, nil
+}
+
+// Resolve implements ReferralResolver.
+func (k *KustomizationResolver) Resolve(
+	_ *resource.Resource, target resid.Gvk, oldName, _ string) (
+	string, string, bool, error) {
+	key := gvkNameKey(target, oldName)
+	for _, path := range k.paths {
+		targets, err := k.targetsFor(path)
+		if err != nil {
+			return "", "", false, err
+		}
+		matches := targets[key]
+		if len(matches) == 1 {
+			return matches[0].GetName(), matches[0].GetNamespace(), true, nil
+		}
+	}
+	// No match, or too ambiguous to pick one automatically.
+	return "", "", false, nil
+}
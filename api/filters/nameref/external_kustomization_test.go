@@ -0,0 +1,55 @@
+package nameref
+
+import (
+	"testing"
+
+	"sigs.k8s.io/kustomize/api/resid"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+func TestKustomizationResolverMatchesIgnoringVersion(t *testing.T) {
+	fSys := filesys.MakeFsInMemory()
+	if err := fSys.WriteFile("/sibling/kustomization.yaml", []byte(`
+resources:
+- configmap.yaml
+`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := fSys.WriteFile("/sibling/configmap.yaml", []byte(`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: my-map
+data:
+  key: value
+`)); err != nil {
+		t.Fatal(err)
+	}
+
+	resolver := NewKustomizationResolver(fSys, "/sibling")
+
+	// target carries no Version, the way f.ReferralTarget usually does
+	// for a builtin kind, while the built ConfigMap's own Gvk is v1.
+	name, namespace, ok, err := resolver.Resolve(
+		nil, resid.Gvk{Kind: "ConfigMap"}, "my-map", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok || name != "my-map" {
+		t.Fatalf("got (%q, %q, %v), want a match on my-map", name, namespace, ok)
+	}
+
+	if _, _, ok, err := resolver.Resolve(
+		nil, resid.Gvk{Kind: "ConfigMap"}, "unknown-map", ""); err != nil || ok {
+		t.Errorf("expected no match for an unlisted name, got ok=%v err=%v", ok, err)
+	}
+}
+
+func TestKustomizationResolverUnbuildableDirectory(t *testing.T) {
+	fSys := filesys.MakeFsInMemory()
+	resolver := NewKustomizationResolver(fSys, "/does-not-exist")
+	if _, _, _, err := resolver.Resolve(
+		nil, resid.Gvk{Kind: "ConfigMap"}, "my-map", ""); err == nil {
+		t.Error("expected an error building a nonexistent sibling kustomization")
+	}
+}
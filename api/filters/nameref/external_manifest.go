@@ -0,0 +1,56 @@
+package nameref
+
+import (
+	"fmt"
+
+	"sigs.k8s.io/kustomize/api/resid"
+	"sigs.k8s.io/kustomize/api/resource"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// ManifestEntry records that a resource of the given Gvk, originally
+// named originalName, is now named currentName (optionally in
+// currentNamespace) in some external build.
+type ManifestEntry struct {
+	Gvk              resid.Gvk `json:"gvk,omitempty" yaml:"gvk,omitempty"`
+	OriginalName     string    `json:"originalName,omitempty" yaml:"originalName,omitempty"`
+	CurrentName      string    `json:"currentName,omitempty" yaml:"currentName,omitempty"`
+	CurrentNamespace string    `json:"currentNamespace,omitempty" yaml:"currentNamespace,omitempty"`
+}
+
+// ManifestResolver is a ReferralResolver backed by a flat list of
+// ManifestEntry tuples, typically loaded from a user-supplied
+// nameReferenceManifest YAML file describing the outcome of a
+// separately built kustomization.
+type ManifestResolver struct {
+	Entries []ManifestEntry
+}
+
+// NewManifestResolver reads and parses a nameReferenceManifest file
+// through fSys, the same filesystem abstraction the rest of the
+// kustomize build uses, so in-memory filesystems and path confinement
+// keep working.
+func NewManifestResolver(fSys filesys.FileSystem, path string) (*ManifestResolver, error) {
+	content, err := fSys.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read name reference manifest %s: %w", path, err)
+	}
+	var entries []ManifestEntry
+	if err := yaml.Unmarshal(content, &entries); err != nil {
+		return nil, fmt.Errorf("unable to parse name reference manifest %s: %w", path, err)
+	}
+	return &ManifestResolver{Entries: entries}, nil
+}
+
+// Resolve implements ReferralResolver.
+func (m *ManifestResolver) Resolve(
+	_ *resource.Resource, target resid.Gvk, oldName, _ string) (
+	string, string, bool, error) {
+	for _, e := range m.Entries {
+		if e.Gvk.Equals(target) && e.OriginalName == oldName {
+			return e.CurrentName, e.CurrentNamespace, true, nil
+		}
+	}
+	return "", "", false, nil
+}
@@ -0,0 +1,47 @@
+package nameref
+
+import (
+	"testing"
+
+	"sigs.k8s.io/kustomize/api/resid"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+func TestManifestResolverResolve(t *testing.T) {
+	fSys := filesys.MakeFsInMemory()
+	if err := fSys.WriteFile("/manifest.yaml", []byte(`
+- gvk:
+    kind: ConfigMap
+  originalName: my-map
+  currentName: my-map-a1b2c3
+  currentNamespace: ns1
+`)); err != nil {
+		t.Fatal(err)
+	}
+
+	resolver, err := NewManifestResolver(fSys, "/manifest.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name, namespace, ok, err := resolver.Resolve(
+		nil, resid.Gvk{Kind: "ConfigMap"}, "my-map", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok || name != "my-map-a1b2c3" || namespace != "ns1" {
+		t.Fatalf("got (%q, %q, %v), want (my-map-a1b2c3, ns1, true)", name, namespace, ok)
+	}
+
+	if _, _, ok, err := resolver.Resolve(
+		nil, resid.Gvk{Kind: "ConfigMap"}, "unknown-map", ""); err != nil || ok {
+		t.Errorf("expected no match for an unlisted name, got ok=%v err=%v", ok, err)
+	}
+}
+
+func TestNewManifestResolverMissingFile(t *testing.T) {
+	fSys := filesys.MakeFsInMemory()
+	if _, err := NewManifestResolver(fSys, "/does-not-exist.yaml"); err == nil {
+		t.Error("expected an error for a missing manifest file")
+	}
+}
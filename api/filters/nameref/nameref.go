@@ -31,8 +31,92 @@ type Filter struct {
 
 	// Set of resources to hunt through to find the ReferralTarget.
 	ReferralCandidates resmap.ResMap
+
+	// ReferralResolution picks the strategy used to disambiguate when
+	// more than one candidate remains after prefix/suffix filtering.
+	// The zero value ("") behaves like ReferralResolutionStrict: any
+	// remaining ambiguity is an error.
+	//
+	// This, and NameReferenceOverrides below, are tagged for direct
+	// embedding in a Kustomization-level config (a `referralResolution:`
+	// / `nameReferenceOverrides:` block plumbed in by
+	// builtins.NameReferenceTransformer); neither exists in this
+	// checkout, so until then these fields are set directly by callers
+	// constructing a Filter in Go.
+	ReferralResolution ReferralResolutionStrategy `json:"referralResolution,omitempty" yaml:"referralResolution,omitempty"`
+
+	// NameReferenceOverrides pin individual name references, bypassing
+	// the generic candidate search (and ReferralResolution) entirely.
+	// They are consulted whenever non-empty, regardless of
+	// ReferralResolution; ReferralResolutionExplicitOverride exists so
+	// callers can name that behavior explicitly in config.
+	NameReferenceOverrides []NameReferenceOverride `json:"nameReferenceOverrides,omitempty" yaml:"nameReferenceOverrides,omitempty"`
+
+	// ExternalResolvers are consulted, in order, for a name reference
+	// that no candidate in ReferralCandidates satisfies. This lets a
+	// reference be updated even when its target was produced by a
+	// separately built, sibling kustomization.
+	ExternalResolvers []ReferralResolver
+
+	// ScopeIndex is the CRD scope lookup for ReferralCandidates, built
+	// once per build via NewScopeIndex and shared across every Filter
+	// created during that build (one per name field, potentially many
+	// per resource). A nil index falls back to scanning
+	// ReferralCandidates on demand, scoped to this one Filter call.
+	ScopeIndex ScopeIndex
+}
+
+// ReferralResolver looks up a name reference's current name/namespace
+// from a source outside the current build.
+type ReferralResolver interface {
+	// Resolve reports the current name and namespace that oldName (of
+	// kind target, referenced from namespace) has become, in whatever
+	// external source the resolver knows about. ok is false if the
+	// resolver has no opinion on this reference.
+	Resolve(referrer *resource.Resource, target resid.Gvk, oldName, namespace string) (
+		newName, newNamespace string, ok bool, err error)
+}
+
+// NameReferenceOverride pins the current name/namespace that a
+// (kind, name) reference resolves to, for the cases the generic
+// search in selectReferral can't disambiguate on its own.
+type NameReferenceOverride struct {
+	From NameReferenceOverrideSide `json:"from,omitempty" yaml:"from,omitempty"`
+	To   NameReferenceOverrideSide `json:"to,omitempty" yaml:"to,omitempty"`
+}
+
+// NameReferenceOverrideSide identifies one side of a
+// NameReferenceOverride.
+type NameReferenceOverrideSide struct {
+	Kind      string `json:"kind,omitempty" yaml:"kind,omitempty"`
+	Name      string `json:"name,omitempty" yaml:"name,omitempty"`
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
 }
 
+// ReferralResolutionStrategy controls how selectReferral disambiguates
+// when more than one candidate survives prefix/suffix filtering.
+type ReferralResolutionStrategy string
+
+const (
+	// ReferralResolutionStrict is the historical behavior: any
+	// remaining ambiguity is an error.
+	ReferralResolutionStrict ReferralResolutionStrategy = "Strict"
+
+	// ReferralResolutionPreferSameNamespace picks the candidate that
+	// shares the referrer's namespace, when exactly one does.
+	ReferralResolutionPreferSameNamespace ReferralResolutionStrategy = "PreferSameNamespace"
+
+	// ReferralResolutionPreferSameComponent picks the candidate that
+	// was produced by the same Kustomization component as the
+	// referrer, when exactly one was.
+	ReferralResolutionPreferSameComponent ReferralResolutionStrategy = "PreferSameComponent"
+
+	// ReferralResolutionExplicitOverride names the behavior already in
+	// effect whenever NameReferenceOverrides is non-empty: the override
+	// list is consulted first and short-circuits the generic search.
+	ReferralResolutionExplicitOverride ReferralResolutionStrategy = "ExplicitOverride"
+)
+
 func (f Filter) Filter(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
 	return kio.FilterAll(yaml.FilterFunc(f.run)).Filter(nodes)
 }
@@ -94,8 +178,17 @@ func (f Filter) setMapping(node *yaml.RNode) error {
 		subset = bynamespace[namespace]
 	}
 
+	// Only fields registered in gvkSiblingFields as self-contained
+	// (parentField == "") are constrained by their kind/apiGroup
+	// siblings; a "kind" field on some other, unrelated mapping-valued
+	// name field is not treated as a reference marker.
+	var constraint *resid.Gvk
+	if loc, ok := f.gvkSiblingLocation(); ok && loc.parentField == "" {
+		constraint = mappingGvkSiblings(node)
+	}
+
 	oldName := nameNode.YNode().Value
-	newName, newNamespace, err := f.selectReferral(oldName, subset)
+	newName, newNamespace, err := f.selectReferral(oldName, subset, constraint)
 	if err != nil {
 		return err
 	}
@@ -123,48 +216,115 @@ func (f Filter) setMapping(node *yaml.RNode) error {
 }
 
 func (f Filter) setScalar(node *yaml.RNode) error {
+	var constraint *resid.Gvk
+	if loc, ok := f.gvkSiblingLocation(); ok && loc.parentField != "" {
+		var err error
+		constraint, err = siblingGvk(
+			f.Referrer, strings.TrimSuffix(f.NameFieldToUpdate.Path, "/name"))
+		if err != nil {
+			return err
+		}
+	}
 	newValue, _, err := f.selectReferral(
 		node.YNode().Value,
-		f.ReferralCandidates.Resources())
+		f.ReferralCandidates.Resources(),
+		constraint)
 	if err != nil {
 		return err
 	}
 	return filtersutil.SetScalar(newValue)(node)
 }
 
-func (f Filter) isRoleRef() bool {
-	return strings.HasSuffix(f.NameFieldToUpdate.Path, "roleRef/name")
+// gvkSiblingLocation declares where to find the Gvk siblings that
+// constrain a reference field's candidates.
+type gvkSiblingLocation struct {
+	// parentField is the reference struct field that holds the sibling
+	// apiGroup/kind for a scalar-valued name field (e.g. "roleRef" for
+	// roleRef/name); setScalar reads the siblings from the mapping one
+	// level above the name field, at whatever depth
+	// NameFieldToUpdate.Path places it. Empty means the siblings live in
+	// the same mapping node as "name" itself, which is the shape of
+	// most reference structs (RBAC subjects, ownerReferences,
+	// dataSourceRef, parametersRef, ...).
+	parentField string
+}
+
+// gvkSiblingFields maps the suffix of NameFieldToUpdate.Path to the
+// location of its Gvk-constraining siblings. Only fields registered
+// here are treated as Gvk-constrained references; an incidental
+// "kind" field on some other mapping is not.
+//
+// The path shape depends on whether the reference struct is itself a
+// list. roleRef, dataSourceRef and parametersRef are single mappings,
+// so the field spec targets the nested "name" scalar directly and the
+// siblings live one level up, under parentField. subjects and
+// ownerReferences are lists of mappings, so the field spec targets the
+// list itself ("subjects", not "subjects/name") and setMapping sees
+// each element whole, siblings and all, with no parentField needed.
+var gvkSiblingFields = map[string]gvkSiblingLocation{
+	"roleRef/name":       {parentField: "roleRef"},
+	"dataSourceRef/name": {parentField: "dataSourceRef"},
+	"parametersRef/name": {parentField: "parametersRef"},
+	"subjects":           {},
+	"ownerReferences":    {},
+}
+
+func (f Filter) gvkSiblingLocation() (gvkSiblingLocation, bool) {
+	for suffix, loc := range gvkSiblingFields {
+		if strings.HasSuffix(f.NameFieldToUpdate.Path, suffix) {
+			return loc, true
+		}
+	}
+	return gvkSiblingLocation{}, false
+}
+
+// mappingGvkSiblings reads the kind/apiGroup (or apiVersion) siblings
+// of "name" directly out of node, for reference fields whose siblings
+// live in the same mapping node. Returns nil if node has no "kind".
+func mappingGvkSiblings(node *yaml.RNode) *resid.Gvk {
+	kindNode, _ := node.Pipe(yaml.FieldMatcher{Name: "kind"})
+	if kindNode == nil {
+		return nil
+	}
+	constraint := &resid.Gvk{Kind: kindNode.YNode().Value}
+	if apiGroupNode, _ := node.Pipe(yaml.FieldMatcher{Name: "apiGroup"}); apiGroupNode != nil {
+		constraint.Group = apiGroupNode.YNode().Value
+	} else if apiVersionNode, _ := node.Pipe(yaml.FieldMatcher{Name: "apiVersion"}); apiVersionNode != nil {
+		constraint.Group = groupFromApiVersion(apiVersionNode.YNode().Value)
+	}
+	return constraint
 }
 
-// getRoleRefGvk returns a Gvk in the roleRef field. Return error
-// if the roleRef, roleRef/apiGroup or roleRef/kind is missing.
-func getRoleRefGvk(res json.Marshaler) (*resid.Gvk, error) {
+// siblingGvk returns the Gvk described by the apiGroup/kind fields
+// nested under parentField (a slash-separated path) in res. Returns an
+// error if parentField, or either sibling, is missing.
+func siblingGvk(res json.Marshaler, parentField string) (*resid.Gvk, error) {
 	n, err := kyaml_filtersutil.GetRNode(res)
 	if err != nil {
 		return nil, err
 	}
-	roleRef, err := n.Pipe(yaml.Lookup("roleRef"))
+	parent, err := n.Pipe(yaml.Lookup(strings.Split(parentField, "/")...))
 	if err != nil {
 		return nil, err
 	}
-	if roleRef.IsNil() {
-		return nil, fmt.Errorf("roleRef cannot be found in %s", n.MustString())
+	if parent.IsNil() {
+		return nil, fmt.Errorf("%s cannot be found in %s", parentField, n.MustString())
 	}
-	apiGroup, err := roleRef.Pipe(yaml.Lookup("apiGroup"))
+	apiGroup, err := parent.Pipe(yaml.Lookup("apiGroup"))
 	if err != nil {
 		return nil, err
 	}
 	if apiGroup.IsNil() {
 		return nil, fmt.Errorf(
-			"apiGroup cannot be found in roleRef %s", roleRef.MustString())
+			"apiGroup cannot be found in %s %s", parentField, parent.MustString())
 	}
-	kind, err := roleRef.Pipe(yaml.Lookup("kind"))
+	kind, err := parent.Pipe(yaml.Lookup("kind"))
 	if err != nil {
 		return nil, err
 	}
 	if kind.IsNil() {
 		return nil, fmt.Errorf(
-			"kind cannot be found in roleRef %s", roleRef.MustString())
+			"kind cannot be found in %s %s", parentField, parent.MustString())
 	}
 	return &resid.Gvk{
 		Group: apiGroup.YNode().Value,
@@ -172,15 +332,155 @@ func getRoleRefGvk(res json.Marshaler) (*resid.Gvk, error) {
 	}, nil
 }
 
+// groupFromApiVersion extracts the group portion of a "group/version"
+// apiVersion string, returning "" for core (groupless) kinds.
+func groupFromApiVersion(apiVersion string) string {
+	if i := strings.Index(apiVersion, "/"); i >= 0 {
+		return apiVersion[:i]
+	}
+	return ""
+}
+
+// resourceScope records whether a Gvk is scoped to a namespace or to
+// the whole cluster.
+type resourceScope string
+
+const (
+	clusterScoped    resourceScope = "Cluster"
+	namespacedScoped resourceScope = "Namespaced"
+	unknownScope     resourceScope = ""
+)
+
+// coreScopes is a built-in table of scopes for well-known Kubernetes
+// kinds, consulted when no CRD manifest describes the kind. Notably,
+// ServiceAccount is absent: its scope is left unknown on purpose, so
+// that it keeps hitting the historical special case in
+// filterReferralCandidates rather than being namespace-filtered.
+var coreScopes = map[string]resourceScope{
+	"ConfigMap":          namespacedScoped,
+	"Secret":             namespacedScoped,
+	"Role":               namespacedScoped,
+	"RoleBinding":        namespacedScoped,
+	"ClusterRole":        clusterScoped,
+	"ClusterRoleBinding": clusterScoped,
+	"Namespace":          clusterScoped,
+	"Node":               clusterScoped,
+	"PersistentVolume":   clusterScoped,
+	"StorageClass":       clusterScoped,
+}
+
+// ScopeIndex is a Gvk-to-scope lookup built by NewScopeIndex. Build it
+// once per kustomize build and set it on every Filter created during
+// that build (via Filter.ScopeIndex) so the CRD scan crdScopes does
+// happens once per build rather than once per ambiguous name
+// reference, without resorting to a process-global cache that would
+// outlive the build and leak in a long-running process.
+type ScopeIndex map[resid.Gvk]resourceScope
+
+// NewScopeIndex scans candidates for CustomResourceDefinition objects
+// and returns the scope each one declares for the Gvk it defines.
+func NewScopeIndex(candidates resmap.ResMap) ScopeIndex {
+	return ScopeIndex(crdScopes(candidates))
+}
+
+// crdScopes scans the referral candidates for CustomResourceDefinition
+// objects and returns the scope each one declares for the Gvk it
+// defines.
+func crdScopes(candidates resmap.ResMap) map[resid.Gvk]resourceScope {
+	scopes := make(map[resid.Gvk]resourceScope)
+	for _, res := range candidates.Resources() {
+		gvk := res.CurId().Gvk
+		if gvk.Kind != "CustomResourceDefinition" ||
+			!strings.HasPrefix(gvk.Group, "apiextensions.k8s.io") {
+			continue
+		}
+		group, err := lookupString(res, "spec", "group")
+		if err != nil {
+			continue
+		}
+		kind, err := lookupString(res, "spec", "names", "kind")
+		if err != nil {
+			continue
+		}
+		scope, err := lookupString(res, "spec", "scope")
+		if err != nil {
+			continue
+		}
+		scopes[resid.Gvk{Group: group, Kind: kind}] = resourceScope(scope)
+	}
+	return scopes
+}
+
+// lookupString reads a nested string field out of a resource.
+func lookupString(res json.Marshaler, path ...string) (string, error) {
+	n, err := kyaml_filtersutil.GetRNode(res)
+	if err != nil {
+		return "", err
+	}
+	field, err := n.Pipe(yaml.Lookup(path...))
+	if err != nil {
+		return "", err
+	}
+	if field.IsNil() {
+		return "", fmt.Errorf("field %v not found in %s", path, n.MustString())
+	}
+	return field.YNode().Value, nil
+}
+
+// scopeOf returns the scope of target, consulting any CRDs found
+// among the referral candidates first, then the built-in table of
+// well-known kinds. It returns unknownScope if neither knows. If
+// target.Group is empty and more than one CRD of that Kind (in
+// different groups) is present, the CRDs are not used, since the
+// match would otherwise be arbitrary; only the built-in table is
+// consulted.
+func (f Filter) scopeOf(target resid.Gvk) resourceScope {
+	scopes := f.ScopeIndex
+	if scopes == nil {
+		scopes = NewScopeIndex(f.ReferralCandidates)
+	}
+	if target.Group != "" {
+		if scope, ok := scopes[target]; ok {
+			return scope
+		}
+	} else {
+		var found resourceScope
+		matches := 0
+		for gvk, scope := range scopes {
+			if gvk.Kind == target.Kind {
+				matches++
+				found = scope
+			}
+		}
+		if matches == 1 {
+			return found
+		}
+	}
+	if scope, ok := coreScopes[target.Kind]; ok {
+		return scope
+	}
+	return unknownScope
+}
+
 func (f Filter) filterReferralCandidates(
 	matches []*resource.Resource) []*resource.Resource {
 	var ret []*resource.Resource
+	scope := f.scopeOf(f.ReferralTarget)
+	// Scope unknown: fall back to the historical ServiceAccount special
+	// case, namespace-filtering everything else.
 	for _, m := range matches {
-		// If target kind is not ServiceAccount, we shouldn't consider condidates which
-		// doesn't have same namespace.
-		if f.ReferralTarget.Kind != "ServiceAccount" &&
-			m.GetNamespace() != f.Referrer.GetNamespace() {
-			continue
+		switch scope {
+		case clusterScoped:
+			// Cluster-scoped targets are never namespace-filtered.
+		case namespacedScoped:
+			if m.GetNamespace() != f.Referrer.GetNamespace() {
+				continue
+			}
+		default:
+			if f.ReferralTarget.Kind != "ServiceAccount" &&
+				m.GetNamespace() != f.Referrer.GetNamespace() {
+				continue
+			}
 		}
 		if !f.Referrer.PrefixesSuffixesEquals(m) {
 			continue
@@ -195,23 +495,19 @@ func (f Filter) filterReferralCandidates(
 // The content of the referricalCandidateSubset slice is most of the time
 // identical to the referralCandidates resmap. Still in some cases, such
 // as ClusterRoleBinding, the subset only contains the resources of a specific
-// namespace.
+// namespace. constraint, when non-nil, further restricts matches to
+// resources whose Gvk it selects (e.g. the kind named by a roleRef or
+// a subjects entry).
 func (f Filter) selectReferral(
 	oldName string,
-	referralCandidateSubset []*resource.Resource) (string, string, error) {
-	var roleRefGvk *resid.Gvk
-	if f.isRoleRef() {
-		var err error
-		roleRefGvk, err = getRoleRefGvk(f.Referrer)
-		if err != nil {
-			return "", "", err
-		}
+	referralCandidateSubset []*resource.Resource,
+	constraint *resid.Gvk) (string, string, error) {
+	if override, ok := f.lookupOverride(oldName); ok {
+		return override.To.Name, override.To.Namespace, nil
 	}
 	for _, res := range referralCandidateSubset {
 		id := res.OrgId()
-		// If the we are processing a roleRef, the apiGroup and Kind in the
-		// roleRef are needed to be considered.
-		if (!f.isRoleRef() || id.IsSelected(roleRefGvk)) &&
+		if (constraint == nil || id.IsSelected(constraint)) &&
 			id.IsSelected(&f.ReferralTarget) && res.GetOriginalName() == oldName {
 			matches := f.ReferralCandidates.GetMatchingResourcesByOriginalId(id.Equals)
 			// If there's more than one match,
@@ -219,9 +515,11 @@ func (f Filter) selectReferral(
 			if len(matches) > 1 {
 				filteredMatches := f.filterReferralCandidates(matches)
 				if len(filteredMatches) > 1 {
-					return "", "", fmt.Errorf(
-						"multiple matches for %s:\n  %v",
-						id, getIds(filteredMatches))
+					resolved, err := f.resolveAmbiguity(filteredMatches)
+					if err != nil {
+						return "", "", err
+					}
+					filteredMatches = resolved
 				}
 				// Check is the match the resource we are working on
 				if len(filteredMatches) == 0 || res != filteredMatches[0] {
@@ -236,9 +534,102 @@ func (f Filter) selectReferral(
 			return res.GetName(), res.GetNamespace(), nil
 		}
 	}
+	for _, resolver := range f.ExternalResolvers {
+		newName, newNamespace, ok, err := resolver.Resolve(
+			f.Referrer, f.ReferralTarget, oldName, f.Referrer.GetNamespace())
+		if err != nil {
+			return "", "", err
+		}
+		if ok {
+			return newName, newNamespace, nil
+		}
+	}
 	return oldName, "", nil
 }
 
+// lookupOverride finds the NameReferenceOverride pinning oldName for
+// the current ReferralTarget kind, if any. An empty o.From.Namespace
+// matches any referrer namespace; a non-empty one must match exactly,
+// so that two namespace-distinguished overrides for the same
+// (kind, name) don't collide.
+func (f Filter) lookupOverride(oldName string) (*NameReferenceOverride, bool) {
+	for i := range f.NameReferenceOverrides {
+		o := &f.NameReferenceOverrides[i]
+		if o.From.Kind != f.ReferralTarget.Kind || o.From.Name != oldName {
+			continue
+		}
+		if o.From.Namespace != "" && o.From.Namespace != f.Referrer.GetNamespace() {
+			continue
+		}
+		return o, true
+	}
+	return nil, false
+}
+
+// resolveAmbiguity narrows matches down to a single candidate using
+// f.ReferralResolution. It errors out, same as the Strict strategy,
+// when the chosen strategy can't settle on exactly one candidate.
+func (f Filter) resolveAmbiguity(
+	matches []*resource.Resource) ([]*resource.Resource, error) {
+	switch f.ReferralResolution {
+	case ReferralResolutionPreferSameNamespace:
+		if m := soleMatch(matches, func(m *resource.Resource) bool {
+			return m.GetNamespace() == f.Referrer.GetNamespace()
+		}); m != nil {
+			return []*resource.Resource{m}, nil
+		}
+	case ReferralResolutionPreferSameComponent:
+		if m := soleMatch(matches, func(m *resource.Resource) bool {
+			return sameComponent(m, f.Referrer)
+		}); m != nil {
+			return []*resource.Resource{m}, nil
+		}
+		if origin, err := f.Referrer.GetOrigin(); err != nil || origin == nil {
+			return nil, fmt.Errorf(
+				"PreferSameComponent could not determine %s's own origin, "+
+					"so it cannot tell which of these matches came from the "+
+					"same component: enable `buildMetadata: "+
+					"[originAnnotations]` in the Kustomization and retry:\n  %v",
+				f.Referrer.CurId(), getIds(matches))
+		}
+	}
+	return nil, fmt.Errorf(
+		"multiple matches for %s:\n  %v",
+		f.ReferralTarget, getIds(matches))
+}
+
+// soleMatch returns the single resource in rs satisfying pred, or nil
+// if zero or more than one do.
+func soleMatch(
+	rs []*resource.Resource, pred func(*resource.Resource) bool) *resource.Resource {
+	var found *resource.Resource
+	for _, r := range rs {
+		if !pred(r) {
+			continue
+		}
+		if found != nil {
+			return nil
+		}
+		found = r
+	}
+	return found
+}
+
+// sameComponent reports whether a and b were produced by the same
+// Kustomization root, using the origin tracked for each resource. It
+// requires `buildMetadata: [originAnnotations]` to have been set on
+// the Kustomization that produced a and b; without it GetOrigin
+// returns nil and this always reports false, degrading
+// ReferralResolutionPreferSameComponent to "no match".
+func sameComponent(a, b *resource.Resource) bool {
+	originA, errA := a.GetOrigin()
+	originB, errB := b.GetOrigin()
+	if errA != nil || errB != nil || originA == nil || originB == nil {
+		return false
+	}
+	return originA.Repo == originB.Repo && originA.Path == originB.Path
+}
+
 func getIds(rs []*resource.Resource) []string {
 	var result []string
 	for _, r := range rs {
@@ -0,0 +1,196 @@
+package nameref
+
+import (
+	"testing"
+
+	"sigs.k8s.io/kustomize/api/provider"
+	"sigs.k8s.io/kustomize/api/resid"
+	"sigs.k8s.io/kustomize/api/resmap"
+	"sigs.k8s.io/kustomize/api/resource"
+	"sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+func TestCoreScopesLeavesServiceAccountUnknown(t *testing.T) {
+	if _, ok := coreScopes["ServiceAccount"]; ok {
+		t.Error("ServiceAccount must be absent from coreScopes so it " +
+			"keeps hitting the unknown-scope fallback in filterReferralCandidates")
+	}
+}
+
+func TestCoreScopesKnownKinds(t *testing.T) {
+	tests := map[string]resourceScope{
+		"ClusterRole":        clusterScoped,
+		"ClusterRoleBinding": clusterScoped,
+		"Namespace":          clusterScoped,
+		"ConfigMap":          namespacedScoped,
+		"RoleBinding":        namespacedScoped,
+	}
+	for kind, want := range tests {
+		if got := coreScopes[kind]; got != want {
+			t.Errorf("coreScopes[%s] = %q, want %q", kind, got, want)
+		}
+	}
+}
+
+func TestGroupFromApiVersion(t *testing.T) {
+	tests := []struct {
+		apiVersion string
+		want       string
+	}{
+		{"v1", ""},
+		{"apps/v1", "apps"},
+		{"rbac.authorization.k8s.io/v1", "rbac.authorization.k8s.io"},
+	}
+	for _, tc := range tests {
+		if got := groupFromApiVersion(tc.apiVersion); got != tc.want {
+			t.Errorf("groupFromApiVersion(%q) = %q, want %q", tc.apiVersion, got, tc.want)
+		}
+	}
+}
+
+func TestGvkSiblingLocation(t *testing.T) {
+	tests := []struct {
+		path       string
+		wantFound  bool
+		wantParent string
+	}{
+		{"roleRef/name", true, "roleRef"},
+		{"subjects", true, ""},
+		{"ownerReferences", true, ""},
+		{"subjects/name", false, ""},
+		{"spec/serviceAccountName", false, ""},
+	}
+	for _, tc := range tests {
+		f := Filter{NameFieldToUpdate: types.FieldSpec{Path: tc.path}}
+		loc, ok := f.gvkSiblingLocation()
+		if ok != tc.wantFound {
+			t.Errorf("gvkSiblingLocation(%q) found = %v, want %v", tc.path, ok, tc.wantFound)
+			continue
+		}
+		if ok && loc.parentField != tc.wantParent {
+			t.Errorf("gvkSiblingLocation(%q) parentField = %q, want %q",
+				tc.path, loc.parentField, tc.wantParent)
+		}
+	}
+}
+
+func TestMappingGvkSiblings(t *testing.T) {
+	node, err := yaml.Parse(`
+kind: ServiceAccount
+name: build-robot
+apiGroup: ""
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	constraint := mappingGvkSiblings(node)
+	if constraint == nil || constraint.Kind != "ServiceAccount" {
+		t.Fatalf("expected a ServiceAccount constraint, got %v", constraint)
+	}
+
+	noKind, err := yaml.Parse(`name: build-robot`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := mappingGvkSiblings(noKind); got != nil {
+		t.Errorf("expected nil constraint without a kind sibling, got %v", got)
+	}
+}
+
+// TestSubjectsUserSubjectNotMistakenForServiceAccount guards against
+// the regression this field spec registry exists to prevent: a
+// ClusterRoleBinding subject of kind User (or Group) must not be
+// rewritten just because some ServiceAccount happens to share its
+// name.
+func TestSubjectsUserSubjectNotMistakenForServiceAccount(t *testing.T) {
+	depProvider := provider.NewDefaultDepProvider()
+	rf := depProvider.GetResourceFactory()
+	rmF := resmap.NewFactory(rf)
+
+	candidates, err := rmF.NewResMapFromBytes([]byte(`
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: my-app
+  namespace: ns1
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := Filter{
+		NameFieldToUpdate:  types.FieldSpec{Path: "subjects"},
+		ReferralTarget:     resid.Gvk{Kind: "ServiceAccount"},
+		ReferralCandidates: candidates,
+	}
+
+	node, err := yaml.Parse(`
+kind: User
+name: my-app
+apiGroup: rbac.authorization.k8s.io
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.setMapping(node); err != nil {
+		t.Fatal(err)
+	}
+
+	nameNode, err := node.Pipe(yaml.FieldMatcher{Name: "name"})
+	if err != nil || nameNode == nil {
+		t.Fatal("name field missing after setMapping")
+	}
+	if got := nameNode.YNode().Value; got != "my-app" {
+		t.Errorf("expected the User subject's name to stay unchanged, got %q", got)
+	}
+}
+
+func TestLookupOverrideMatchesKindAndName(t *testing.T) {
+	f := Filter{
+		ReferralTarget: resid.Gvk{Kind: "ConfigMap"},
+		NameReferenceOverrides: []NameReferenceOverride{
+			{
+				From: NameReferenceOverrideSide{Kind: "ConfigMap", Name: "old"},
+				To:   NameReferenceOverrideSide{Name: "new", Namespace: "ns2"},
+			},
+		},
+	}
+	o, ok := f.lookupOverride("old")
+	if !ok || o.To.Name != "new" || o.To.Namespace != "ns2" {
+		t.Fatalf("expected override to match, got %v, %v", o, ok)
+	}
+	if _, ok := f.lookupOverride("other"); ok {
+		t.Error("expected no override for a different name")
+	}
+
+	f.ReferralTarget = resid.Gvk{Kind: "Secret"}
+	if _, ok := f.lookupOverride("old"); ok {
+		t.Error("expected no override for a different kind")
+	}
+}
+
+func TestReferralResolutionZeroValueIsStrict(t *testing.T) {
+	f := Filter{ReferralTarget: resid.Gvk{Kind: "ConfigMap"}}
+	if f.ReferralResolution != "" {
+		t.Fatalf("expected zero value, got %q", f.ReferralResolution)
+	}
+	if _, err := f.resolveAmbiguity(nil); err == nil {
+		t.Error("expected the zero-value strategy to behave like Strict and error out")
+	}
+}
+
+func TestSoleMatch(t *testing.T) {
+	a, b, c := new(resource.Resource), new(resource.Resource), new(resource.Resource)
+	rs := []*resource.Resource{a, b, c}
+
+	if got := soleMatch(rs, func(r *resource.Resource) bool { return r == b }); got != b {
+		t.Errorf("expected to find the sole match b, got %v", got)
+	}
+	if got := soleMatch(rs, func(r *resource.Resource) bool { return r == a || r == b }); got != nil {
+		t.Errorf("expected nil for more than one match, got %v", got)
+	}
+	if got := soleMatch(rs, func(*resource.Resource) bool { return false }); got != nil {
+		t.Errorf("expected nil for zero matches, got %v", got)
+	}
+}